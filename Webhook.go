@@ -1,141 +1,58 @@
 package main
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"crypto/subtle"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
 
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/bridgestate"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/logging"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/messagestore"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/metrics"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/provisioning"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/webhook"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/webhook/security"
 	wb "github.com/JeremyJalpha/WhatsAppBot/whatsappbot"
+	"github.com/febriliankr/whatsapp-cloud-api"
 )
 
-type StatusesWebhookRequest struct {
-	Object string `json:"object"`
-	Entry  []struct {
-		ID      string `json:"id"`
-		Changes []struct {
-			Value struct {
-				MessagingProduct string `json:"messaging_product"`
-				Metadata         struct {
-					DisplayPhoneNumber string `json:"display_phone_number"`
-					PhoneNumberID      string `json:"phone_number_id"`
-				} `json:"metadata"`
-				Statuses []struct {
-					ID           string `json:"id"`
-					Status       string `json:"status"`
-					Timestamp    string `json:"timestamp"`
-					RecipientID  string `json:"recipient_id"`
-					Conversation struct {
-						ID                  string `json:"id"`
-						ExpirationTimestamp string `json:"expiration_timestamp"`
-						Origin              struct {
-							Type string `json:"type"`
-						} `json:"origin"`
-					} `json:"conversation"`
-					Pricing struct {
-						Billable     bool   `json:"billable"`
-						PricingModel string `json:"pricing_model"`
-						Category     string `json:"category"`
-					} `json:"pricing"`
-				} `json:"statuses"`
-			} `json:"value"`
-			Field string `json:"field"`
-		} `json:"changes"`
-	} `json:"entry"`
-}
-
-type ContactsWebhookRequest struct {
-	Object string `json:"object"`
-	Entry  []struct {
-		ID      string `json:"id"`
-		Changes []struct {
-			Value struct {
-				MessagingProduct string `json:"messaging_product"`
-				Metadata         struct {
-					DisplayPhoneNumber string `json:"display_phone_number"`
-					PhoneNumberID      string `json:"phone_number_id"`
-				} `json:"metadata"`
-				Contacts []struct {
-					Profile struct {
-						Name string `json:"name"`
-					} `json:"profile"`
-					WaID string `json:"wa_id"`
-				} `json:"contacts"`
-				Messages []struct {
-					From      string `json:"from"`
-					ID        string `json:"id"`
-					Timestamp string `json:"timestamp"`
-					Text      struct {
-						Body string `json:"body"`
-					} `json:"text"`
-					Type string `json:"type"`
-				} `json:"messages"`
-			} `json:"value"`
-			Field string `json:"field"`
-		} `json:"changes"`
-	} `json:"entry"`
-}
-
-// VerificationHandler handles the GET /webhook route for verification
-func VerificationHandler(verifyToken string) http.HandlerFunc {
+// VerificationHandler handles the GET /webhook route for verification.
+// Meta's handshake carries no tenant identifier, so the proffered token is
+// matched against every registered tenant's verify token.
+func VerificationHandler(store *provisioning.Store, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		reqLogger := logging.FromContext(r.Context(), logger)
 		profferedToken := r.URL.Query().Get("hub.verify_token")
 		challenge := r.URL.Query().Get("hub.challenge")
 
-		if profferedToken == verifyToken {
+		if _, err := store.GetByVerifyToken(profferedToken); err == nil {
 			w.WriteHeader(http.StatusOK)
 			_, err := w.Write([]byte(challenge))
 			if err != nil {
 				http.Error(w, "Internal Server Error.", http.StatusInternalServerError)
-				log.Println(err)
+				reqLogger.Error("error writing verification response", "error", err)
 				return
 			}
-			log.Println("Webhook verified.")
+			reqLogger.Info("webhook verified")
 		} else {
-			err := "Error, wrong validation token."
 			w.WriteHeader(http.StatusForbidden)
-			_, sendErr := w.Write([]byte(err))
+			_, sendErr := w.Write([]byte("Error, wrong validation token."))
 			if sendErr != nil {
 				http.Error(w, "Internal Server Error.", http.StatusInternalServerError)
-				log.Println(sendErr)
+				reqLogger.Error("error writing verification rejection", "error", sendErr)
 				return
 			}
-			log.Println(err)
+			reqLogger.Warn("webhook verification failed: wrong validation token")
 		}
 	}
 }
 
-// CalculateSignature calculates the signature for the Facebook webhook payload.
-func CalculateSignatureSha256(payload, secret []byte) string {
-	mac := hmac.New(sha256.New, secret)
-	mac.Write(payload)
-	rawHmac := mac.Sum(nil)
-	return hex.EncodeToString(rawHmac)
-}
-
-// Taken from: https://stackoverflow.com/questions/38353831/facebook-webhook-signature-calculation-c
-func EscapeNonASCIICharacters(s string) string {
-	var escaped string
-	for _, c := range s {
-		if c > 127 {
-			escaped += fmt.Sprintf("\\u%04X", unicode.ToUpper(c))
-		} else {
-			escaped += string(c)
-		}
-	}
-	return escaped
-}
-
 // Checks whether the message is older than the parmater staleMsg in minutes
 func IsMessageStale(timestamp string, staleMsg int) bool {
 
@@ -150,87 +67,237 @@ func IsMessageStale(timestamp string, staleMsg int) bool {
 	return now.Sub(time.Unix(timeInt, 0)) >= time.Duration(staleMsg)*time.Minute
 }
 
-// IsMessageValid returns the body of the last message
-// returns lastMesasgeBody, lastMsgTimeStamp, error
-func IsMessageValid(req ContactsWebhookRequest, staleMsg int) (string, string, error) {
-	lastEntry := req.Entry[len(req.Entry)-1]
-	lastChange := lastEntry.Changes[len(lastEntry.Changes)-1]
-	lastMessage := lastChange.Value.Messages[len(lastChange.Value.Messages)-1]
-	lastMsgTimeStamp := lastMessage.Timestamp
-	MessageBody := strings.ToLower(lastMessage.Text.Body)
-	recipientNum := lastMessage.From
+// resolveTenant looks up the tenant that should service payload, preferring
+// the metadata.phone_number_id on each change, since that is the identifier
+// that is actually 1:1 with a tenant, and falling back to the entry's WABA
+// ID only when no phone_number_id is present. A single WABA ID can have
+// several registered phone numbers, so matching on it first would resolve
+// every number under that account to whichever tenant Postgres happens to
+// return first.
+func resolveTenant(store *provisioning.Store, payload webhook.InboundWebhook) (*provisioning.Tenant, error) {
+	for _, entry := range payload.Entry {
+		sawPhoneNumberID := false
+		for _, change := range entry.Changes {
+			phoneNumberID := change.Value.Metadata.PhoneNumberID
+			if phoneNumberID == "" {
+				continue
+			}
+			sawPhoneNumberID = true
+			if tenant, err := store.GetByPhoneNumberID(phoneNumberID); err == nil {
+				return tenant, nil
+			}
+		}
+		if !sawPhoneNumberID && entry.ID != "" {
+			if tenant, err := store.GetByWabaID(entry.ID); err == nil {
+				return tenant, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("error, no tenant registered for this webhook payload")
+}
+
+// chatClientHandler adapts a tenant's wb.ChatClient to webhook.Handler. Only
+// text messages drive a conversation today; every other message type is
+// logged so bot authors know which flows still need to be built out.
+type chatClientHandler struct {
+	tenant   *provisioning.Tenant
+	db       *sql.DB
+	staleMsg int
+	tracker  *bridgestate.Tracker
+	messages *messagestore.Store
+	logger   *slog.Logger
+}
+
+func (h *chatClientHandler) OnText(from string, msg webhook.Message, text webhook.TextMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeText)).Inc()
+
+	inserted, err := h.messages.InsertInbound(h.tenant.ID, msg.ID, from)
+	if err != nil {
+		h.logger.Error("error recording inbound message", "message_id", msg.ID, "error", err)
+	} else if !inserted {
+		h.logger.Info("duplicate inbound message, skipping", "message_id", msg.ID)
+		return
+	}
+
+	if IsMessageStale(msg.Timestamp, h.staleMsg) {
+		metrics.StaleMessagesTotal.Inc()
+		h.logger.Info("message was invalid: stale message", "message_id", msg.ID)
+		return
+	}
+	messageBody := strings.ToLower(text.Body)
+	if from == h.tenant.HostNumber {
+		h.logger.Info("you sent a message", "body", messageBody)
+		return
+	}
+
+	c := &wb.ChatClient{Whatsapp: whatsapp.NewWhatsapp(h.tenant.WhatsAppToken, h.tenant.PhoneNumberID)}
+	checkoutUrls := wb.CheckoutInfo{
+		ReturnURL:      h.tenant.HomebaseURL + returnBaseURL,
+		CancelURL:      h.tenant.HomebaseURL + cancelBaseURL,
+		NotifyURL:      h.tenant.HomebaseURL + notifyBaseURL,
+		MerchantId:     h.tenant.MerchantId,
+		MerchantKey:    h.tenant.MerchantKey,
+		Passphrase:     h.tenant.Passphrase,
+		HostURL:        h.tenant.PfHost,
+		ItemNamePrefix: ItemNamePrefix,
+	}
+	convo := wb.NewConversationContext(h.db, from, messageBody, isAutoInc)
+
+	chatBeginStart := time.Now()
+	sendErr := c.ChatBegin(*convo, h.db, checkoutUrls, isAutoInc)
+	metrics.ChatBeginDurationSeconds.Observe(time.Since(chatBeginStart).Seconds())
+
+	result := "ok"
+	if sendErr != nil {
+		result = "error"
+		h.logger.Error("error sending reply", "error", sendErr)
+	}
+	metrics.OutboundSendDurationSeconds.WithLabelValues(result).Observe(time.Since(chatBeginStart).Seconds())
+	h.tracker.RecordSendResult(h.tenant.ID, sendErr)
+
+	if err := h.messages.MarkInboundProcessed(h.tenant.ID, msg.ID); err != nil {
+		h.logger.Error("error marking inbound message processed", "message_id", msg.ID, "error", err)
+	}
+}
+
+func (h *chatClientHandler) OnImage(from string, msg webhook.Message, image webhook.MediaMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeImage)).Inc()
+	h.logger.Info("image messages not yet handled", "media_id", image.ID)
+}
+
+func (h *chatClientHandler) OnAudio(from string, msg webhook.Message, audio webhook.MediaMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeAudio)).Inc()
+	h.logger.Info("audio messages not yet handled", "media_id", audio.ID)
+}
+
+func (h *chatClientHandler) OnVideo(from string, msg webhook.Message, video webhook.MediaMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeVideo)).Inc()
+	h.logger.Info("video messages not yet handled", "media_id", video.ID)
+}
+
+func (h *chatClientHandler) OnDocument(from string, msg webhook.Message, document webhook.MediaMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeDocument)).Inc()
+	h.logger.Info("document messages not yet handled", "media_id", document.ID)
+}
+
+func (h *chatClientHandler) OnSticker(from string, msg webhook.Message, sticker webhook.MediaMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeSticker)).Inc()
+	h.logger.Info("sticker messages not yet handled", "media_id", sticker.ID)
+}
+
+func (h *chatClientHandler) OnLocation(from string, msg webhook.Message, location webhook.LocationMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeLocation)).Inc()
+	h.logger.Info("location messages not yet handled")
+}
+
+func (h *chatClientHandler) OnContacts(from string, msg webhook.Message, contacts []webhook.Contact) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeContacts)).Inc()
+	h.logger.Info("contact-card messages not yet handled")
+}
+
+func (h *chatClientHandler) OnInteractive(from string, msg webhook.Message, interactive webhook.InteractiveMsg) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeInteractive)).Inc()
+	h.logger.Info("interactive replies not yet handled")
+}
+
+func (h *chatClientHandler) OnButton(from string, msg webhook.Message, button webhook.ButtonMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeButton)).Inc()
+	h.logger.Info("button replies not yet handled")
+}
+
+func (h *chatClientHandler) OnReaction(from string, msg webhook.Message, reaction webhook.ReactionMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeReaction)).Inc()
+	h.logger.Info("reactions not yet handled")
+}
+
+func (h *chatClientHandler) OnOrder(from string, msg webhook.Message, order webhook.OrderMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeOrder)).Inc()
+	h.logger.Info("catalog orders not yet handled")
+}
+
+func (h *chatClientHandler) OnSystem(from string, msg webhook.Message, system webhook.SystemMessage) {
+	metrics.MessagesProcessedTotal.WithLabelValues(string(webhook.MessageTypeSystem)).Inc()
+	h.logger.Info("system messages not yet handled")
+}
+
+func (h *chatClientHandler) OnStatus(status webhook.Status) {
+	at := time.Now()
+	if timeInt, err := strconv.ParseInt(status.Timestamp, 10, 64); err == nil {
+		at = time.Unix(timeInt, 0)
+	}
 
-	if lastMsgTimeStamp == "" || lastMsgTimeStamp == "-1" {
-		return "Err:FailedToGetLastMsgTimeStamp", "-1", fmt.Errorf("error failed to get last message timestamp")
+	var errCode int
+	var errTitle string
+	if len(status.Errors) > 0 {
+		errCode = status.Errors[0].Code
+		errTitle = status.Errors[0].Title
 	}
-	if IsMessageStale(lastMsgTimeStamp, staleMsg) {
-		return "Err:StaleMessage", "-1", fmt.Errorf("error message was stale")
+
+	if err := h.messages.UpdateOutboundStatus(h.tenant.ID, status.ID, status.Status, at, errCode, errTitle); err != nil {
+		h.logger.Error("error recording outbound status", "wamid", status.ID, "error", err)
 	}
-	return MessageBody, recipientNum, nil
+}
+
+func (h *chatClientHandler) OnUnknown(from string, msg webhook.Message) {
+	h.logger.Warn("unhandled message type", "type", msg.Type)
 }
 
 // WebhookHandler handles the POST /webhook route
-func WebhookHandler(appSecret, hostNumber string, staleMsg int, c *wb.ChatClient, db *sql.DB, checkoutUrls wb.CheckoutInfo) http.HandlerFunc {
+func WebhookHandler(store *provisioning.Store, staleMsg int, db *sql.DB, tracker *bridgestate.Tracker, messages *messagestore.Store, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var err error
-
-		// Verify signature
-		signature256 := strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
-		if signature256 == "" {
-			err := "error, signature is missing"
-			http.Error(w, err, http.StatusForbidden)
-			log.Println(err)
-			return
-		}
+		reqLogger := logging.FromContext(r.Context(), logger)
 
 		// Read the request body
 		byteBody, err := io.ReadAll(r.Body)
 		if err != nil {
+			metrics.WebhookRequestsTotal.WithLabelValues("read_error").Inc()
 			http.Error(w, "error reading request body.", http.StatusInternalServerError)
-			log.Println("error reading request body: " + err.Error())
+			reqLogger.Error("error reading request body", "error", err)
 			return
 		}
 
-		calculatedSignature256 := CalculateSignatureSha256([]byte(EscapeNonASCIICharacters(string(byteBody))), []byte(appSecret))
-		if subtle.ConstantTimeCompare([]byte(calculatedSignature256), []byte(signature256)) != 1 {
-			err := "error signatures do not match"
-			http.Error(w, err, http.StatusForbidden)
-			log.Println(err + "\nExpected Sha256: " + signature256 + "\nbut got Sha256: " + calculatedSignature256)
+		// Decode the request body once so the tenant can be identified
+		// before the signature is checked against its secret, and so the
+		// same payload can be fanned out to typed handlers below.
+		var payload webhook.InboundWebhook
+		if err = json.Unmarshal(byteBody, &payload); err != nil {
+			metrics.WebhookRequestsTotal.WithLabelValues("bad_json").Inc()
+			http.Error(w, "error parsing JSON.", http.StatusBadRequest)
+			reqLogger.Error("error parsing JSON", "error", err)
 			return
 		}
 
-		// Respond to the webhook request
-		w.WriteHeader(http.StatusOK)
-		_, err = w.Write([]byte("Success"))
+		tenant, err := resolveTenant(store, payload)
 		if err != nil {
-			log.Println("error writing response: ", err)
-		}
-
-		bodyStr := string(byteBody)
-		if strings.Contains(bodyStr, "\"statuses\":[{\"id\":\"") {
-			log.Println("Status updates unhandled at this time.")
+			metrics.WebhookRequestsTotal.WithLabelValues("unknown_tenant").Inc()
+			http.Error(w, err.Error(), http.StatusNotFound)
+			reqLogger.Warn("rejected webhook request: unknown tenant")
 			return
 		}
+		logging.SetTenantID(r.Context(), tenant.ID)
+		reqLogger = logging.FromContext(r.Context(), logger)
 
-		// Parse the request body from the JSON string
-		var req ContactsWebhookRequest
-		err = json.Unmarshal(byteBody, &req)
-		if err != nil {
-			log.Println("error parsing JSON: ", err)
+		if err := security.ValidateSignature(r.Header, byteBody, []byte(tenant.AppSecret), []byte(tenant.PreviousAppSecret)); err != nil {
+			metrics.SignatureFailuresTotal.Inc()
+			metrics.WebhookRequestsTotal.WithLabelValues("signature_mismatch").Inc()
+			http.Error(w, "error signatures do not match", http.StatusForbidden)
+			reqLogger.Warn("rejected webhook request: signature mismatch", "error", err)
 			return
 		}
 
-		messageBody, senderNumber, err := IsMessageValid(req, staleMsg)
+		tracker.RecordWebhookReceived(tenant.ID)
+		metrics.WebhookRequestsTotal.WithLabelValues("ok").Inc()
+
+		// Respond to the webhook request
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write([]byte("Success"))
 		if err != nil {
-			log.Println("Message was invalid: " + err.Error())
-			return
+			reqLogger.Error("error writing response", "error", err)
 		}
 
-		if senderNumber != hostNumber {
-			convo := wb.NewConversationContext(db, senderNumber, messageBody, isAutoInc)
-			c.ChatBegin(*convo, db, checkoutUrls, isAutoInc)
-		} else {
-			log.Println("You sent a message:", messageBody)
-		}
+		webhook.Dispatch(payload, &chatClientHandler{
+			tenant: tenant, db: db, staleMsg: staleMsg, tracker: tracker, messages: messages, logger: reqLogger,
+		})
 	}
 }
@@ -0,0 +1,137 @@
+// Package bridgestate tracks per-tenant connection health — borrowed from
+// mautrix-whatsapp's bridge state pattern — so operators can see a dead
+// tenant's webhook go quiet or its token expire instead of only noticing
+// when a customer complains.
+package bridgestate
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is one of the liveness states a tenant can be in.
+type Event string
+
+const (
+	EventConnected           Event = "CONNECTED"
+	EventBadCredentials      Event = "BAD_CREDENTIALS"
+	EventTransientDisconnect Event = "TRANSIENT_DISCONNECT"
+	EventUnknownError        Event = "UNKNOWN_ERROR"
+)
+
+// State is the liveness snapshot returned by GET /bridgestate for one
+// tenant.
+type State struct {
+	RemoteID  string    `json:"remote_id"`
+	State     Event     `json:"state_event"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`
+}
+
+type tenantHealth struct {
+	state                 State
+	lastWebhookAt         time.Time
+	lastSendAt            time.Time
+	consecutiveSendErrors int
+}
+
+// Tracker holds the latest liveness signal for every tenant it has heard
+// from, and optionally pushes state transitions to an operator-supplied
+// webhook URL.
+type Tracker struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantHealth
+	notify  func(State)
+}
+
+// NewTracker creates a Tracker. notify is called, outside the lock, every
+// time a tenant's state transitions; pass nil to disable push notifications.
+func NewTracker(notify func(State)) *Tracker {
+	return &Tracker{
+		tenants: make(map[string]*tenantHealth),
+		notify:  notify,
+	}
+}
+
+func (t *Tracker) health(tenantID string) *tenantHealth {
+	h, ok := t.tenants[tenantID]
+	if !ok {
+		h = &tenantHealth{state: State{RemoteID: tenantID, State: EventUnknownError}}
+		t.tenants[tenantID] = h
+	}
+	return h
+}
+
+// RecordWebhookReceived marks tenantID as having just received a webhook
+// delivery, which is itself evidence the subscription is alive.
+func (t *Tracker) RecordWebhookReceived(tenantID string) {
+	t.mu.Lock()
+	h := t.health(tenantID)
+	h.lastWebhookAt = time.Now()
+	t.mu.Unlock()
+
+	t.transition(tenantID, EventConnected, "webhook received")
+}
+
+// RecordSendResult reports the outcome of an attempt to send a message to
+// tenantID. A nil err means the send succeeded; otherwise the error is
+// inspected for the Graph API's stale-token signature (HTTP 401) so a dead
+// token is distinguished from an ordinary network hiccup.
+func (t *Tracker) RecordSendResult(tenantID string, err error) {
+	t.mu.Lock()
+	h := t.health(tenantID)
+	h.lastSendAt = time.Now()
+	if err == nil {
+		h.consecutiveSendErrors = 0
+	} else {
+		h.consecutiveSendErrors++
+	}
+	consecutiveSendErrors := h.consecutiveSendErrors
+	t.mu.Unlock()
+
+	switch {
+	case err == nil:
+		t.transition(tenantID, EventConnected, "message sent")
+	case isStaleTokenError(err):
+		t.transition(tenantID, EventBadCredentials, err.Error())
+	case consecutiveSendErrors >= 3:
+		t.transition(tenantID, EventUnknownError, err.Error())
+	default:
+		t.transition(tenantID, EventTransientDisconnect, err.Error())
+	}
+}
+
+// isStaleTokenError reports whether err looks like the Graph API's response
+// to an expired or revoked access token.
+func isStaleTokenError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "401") || strings.Contains(msg, "invalid oauth access token") ||
+		strings.Contains(msg, "session has expired")
+}
+
+func (t *Tracker) transition(tenantID string, event Event, reason string) {
+	t.mu.Lock()
+	h := t.health(tenantID)
+	changed := h.state.State != event
+	h.state = State{RemoteID: tenantID, State: event, Timestamp: time.Now(), Reason: reason}
+	state := h.state
+	t.mu.Unlock()
+
+	if changed && t.notify != nil {
+		t.notify(state)
+	}
+}
+
+// Snapshot returns the current state of every tenant the Tracker has heard
+// from.
+func (t *Tracker) Snapshot() map[string]State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := make(map[string]State, len(t.tenants))
+	for id, h := range t.tenants {
+		snapshot[id] = h.state
+	}
+	return snapshot
+}
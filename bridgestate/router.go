@@ -0,0 +1,50 @@
+package bridgestate
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Router serves GET /bridgestate, gated behind the same shared-secret
+// scheme as the provisioning API, returning every tenant's latest State.
+func Router(sharedSecret string, tracker *Tracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proffered := r.Header.Get("X-Provisioning-Secret")
+		if subtle.ConstantTimeCompare([]byte(proffered), []byte(sharedSecret)) != 1 {
+			http.Error(w, "error, invalid provisioning secret", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(tracker.Snapshot()); err != nil {
+			log.Println("error encoding bridgestate response: " + err.Error())
+		}
+	})
+}
+
+// NotifyWebhook returns a Tracker notify func that POSTs each state
+// transition to url as JSON, so operators get pushed a message instead of
+// having to poll GET /bridgestate.
+func NotifyWebhook(url string) func(State) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func(state State) {
+		body, err := json.Marshal(state)
+		if err != nil {
+			log.Println("error marshaling bridgestate notification: " + err.Error())
+			return
+		}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Println("error sending bridgestate notification: " + err.Error())
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Println("bridgestate notification rejected with status", resp.StatusCode)
+		}
+	}
+}
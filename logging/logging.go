@@ -0,0 +1,94 @@
+// Package logging provides a structured logger and the context plumbing
+// needed to follow a single inbound webhook across parsing, DB writes, and
+// outbound Graph API calls.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+)
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	tenantIDKey
+)
+
+// New builds the process-wide structured logger. JSON output is used so log
+// lines can be shipped to an aggregator without reformatting.
+func New() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stdout, nil))
+}
+
+// WithRequestID returns a context carrying id, retrievable with
+// RequestIDFromContext.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or "" if none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// tenantIDBox is a mutable holder for a tenant ID, installed on the request
+// context once by Recoverer. A handler several stack frames below Recoverer
+// cannot make Recoverer's deferred recover() see a tenant ID by building a
+// new context and reassigning its own local *http.Request — that reassignment
+// never reaches Recoverer's copy of the request. Because the box is shared
+// by pointer, SetTenantID mutates it in place and the change is visible to
+// every context derived from the one Recoverer installed, including
+// Recoverer's own, without any request reassignment.
+type tenantIDBox struct {
+	id string
+}
+
+// WithTenantIDBox returns a context carrying an empty, mutable tenant ID box,
+// to be filled in later (possibly deep in a handler) with SetTenantID.
+func WithTenantIDBox(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantIDKey, &tenantIDBox{})
+}
+
+// SetTenantID fills in the tenant ID box installed on ctx by
+// WithTenantIDBox, if any. It is a no-op if ctx carries no box.
+func SetTenantID(ctx context.Context, tenantID string) {
+	if box, ok := ctx.Value(tenantIDKey).(*tenantIDBox); ok {
+		box.id = tenantID
+	}
+}
+
+// TenantIDFromContext returns the tenant ID stored in ctx, or "" if none.
+func TenantIDFromContext(ctx context.Context) string {
+	box, ok := ctx.Value(tenantIDKey).(*tenantIDBox)
+	if !ok {
+		return ""
+	}
+	return box.id
+}
+
+// FromContext returns logger annotated with whichever of request ID and
+// tenant ID are present on ctx.
+func FromContext(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if id := TenantIDFromContext(ctx); id != "" {
+		logger = logger.With("tenant_id", id)
+	}
+	return logger
+}
+
+// NewRequestID generates a random hex request ID for inbound requests that
+// don't carry their own X-Request-Id.
+func NewRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// RequestID is chi middleware that accepts Meta's X-Request-Id if present,
+// otherwise generates one, and stores it on the request context so every
+// log line for this request can be correlated.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" {
+			id = NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(WithRequestID(r.Context(), id))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Recoverer is chi middleware that turns a panic into a 500 response instead
+// of crashing the process, logging the tenant ID (if the panicking handler
+// had set one on the request context) alongside the recovered value.
+//
+// It installs an empty, mutable tenant ID box on the request context before
+// dispatching to next, so a handler several frames below — which cannot
+// reassign Recoverer's own r — can still fill in the tenant ID by calling
+// SetTenantID(r.Context(), ...) once it's known. The deferred recover()
+// below reads that same box by the pointer it shares with every context
+// derived from r, so it sees the tenant ID even though r is not reassigned
+// again after the box is installed.
+func Recoverer(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(WithTenantIDBox(r.Context()))
+			defer func() {
+				if rec := recover(); rec != nil {
+					FromContext(r.Context(), logger).Error("panic recovered",
+						"error", rec, "path", r.URL.Path)
+					http.Error(w, "Internal Server Error.", http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
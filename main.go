@@ -11,8 +11,11 @@ import (
 
 	"database/sql"
 
-	wb "github.com/JeremyJalpha/WhatsAppBot/whatsappbot"
-	"github.com/febriliankr/whatsapp-cloud-api"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/bridgestate"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/logging"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/messagestore"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/metrics"
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/provisioning"
 	"github.com/go-chi/chi/v5"
 
 	_ "github.com/lib/pq"
@@ -20,6 +23,9 @@ import (
 
 const (
 	webhookURL          = "/webhook"
+	provisioningURL     = "/provision/v1"
+	bridgestateURL      = "/bridgestate"
+	metricsURL          = "/metrics"
 	staleMsgTimeOut int = 10
 	pymntRtrnBase       = "payment_return"
 	pymntCnclBase       = "payment_canceled"
@@ -31,17 +37,11 @@ const (
 )
 
 type EnvVars struct {
-	Port          string
-	VerifyToken   string
-	WhatsAppToken string
-	DBConn        string
-	HostNumber    string
-	PhoneID       string
-	HomebaseURL   string
-	MerchantId    string
-	MerchantKey   string
-	Passphrase    string
-	PfHost        string
+	Port               string
+	DBConn             string
+	ProvisioningSecret string
+	Passphrase         string
+	PfHost             string
 }
 
 func getEnvVar(name string) string {
@@ -52,30 +52,21 @@ func getEnvVar(name string) string {
 	return value
 }
 
-// TODO: if WhatsApp token is stale app just exits silently without error or warning - please fix.
 func main() {
 	envVars := EnvVars{
-		Port:          getEnvVar("PORT"),
-		VerifyToken:   getEnvVar("VERIFY_TOKEN"),
-		WhatsAppToken: getEnvVar("WHATSAPP_TOKEN"),
-		DBConn:        getEnvVar("DATABASE_URL"),
-		HostNumber:    getEnvVar("HOST_NUMBER"),
-		PhoneID:       getEnvVar("PHONE_ID"),
-		HomebaseURL:   getEnvVar("HOMEBASEURL"),
-		MerchantId:    getEnvVar("MERCHANTID"),
-		MerchantKey:   getEnvVar("MERCHANTKEY"),
-		Passphrase:    getEnvVar("PASSPHRASE"),
-		PfHost:        getEnvVar("PFHOST"),
+		Port:               getEnvVar("PORT"),
+		DBConn:             getEnvVar("DATABASE_URL"),
+		ProvisioningSecret: getEnvVar("PROVISIONING_SECRET"),
+		Passphrase:         getEnvVar("PASSPHRASE"),
+		PfHost:             getEnvVar("PFHOST"),
 	}
-	log.Println("Using DB connection string: " + envVars.DBConn)
+	logger := logging.New()
+	logger.Info("using DB connection string", "db_conn", envVars.DBConn)
 
 	bgCtx := context.Background()
 	_, cancel := context.WithTimeout(bgCtx, 10*time.Second)
 	defer cancel()
 
-	//Initialize a new WhatsApp instance
-	wa := whatsapp.NewWhatsapp(envVars.WhatsAppToken, envVars.PhoneID)
-
 	// Open the database connection
 	db, err := sql.Open("postgres", envVars.DBConn)
 	if err != nil {
@@ -87,6 +78,22 @@ func main() {
 		}
 	}()
 
+	tenantStore, err := provisioning.NewStore(db)
+	if err != nil {
+		log.Fatal("Error initializing tenant store: ", err)
+	}
+
+	messageStore, err := messagestore.NewStore(db)
+	if err != nil {
+		log.Fatal("Error initializing message store: ", err)
+	}
+
+	var notify func(bridgestate.State)
+	if notifyURL, ok := os.LookupEnv("BRIDGESTATE_NOTIFY_URL"); ok && notifyURL != "" {
+		notify = bridgestate.NotifyWebhook(notifyURL)
+	}
+	tracker := bridgestate.NewTracker(notify)
+
 	// Get the current working directory
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -101,24 +108,14 @@ func main() {
 	pymntCnclTpl := template.Must(template.ParseFiles(pymntCnclTplPath))
 
 	r := chi.NewRouter()
-
-	checkoutInfo := wb.CheckoutInfo{
-		ReturnURL:      envVars.HomebaseURL + returnBaseURL,
-		CancelURL:      envVars.HomebaseURL + cancelBaseURL,
-		NotifyURL:      envVars.HomebaseURL + notifyBaseURL,
-		MerchantId:     envVars.MerchantId,
-		MerchantKey:    envVars.MerchantKey,
-		Passphrase:     envVars.Passphrase,
-		HostURL:        envVars.PfHost,
-		ItemNamePrefix: ItemNamePrefix,
-	}
+	r.Use(logging.RequestID, logging.Recoverer(logger))
 
 	// Define routes
-	chatClient := wb.ChatClient{
-		Whatsapp: wa,
-	}
-	r.Post(webhookURL, WebhookHandler(envVars.VerifyToken, envVars.HostNumber, staleMsgTimeOut, &chatClient, db, checkoutInfo))
-	r.Get(webhookURL, VerificationHandler(envVars.VerifyToken))
+	r.Post(webhookURL, WebhookHandler(tenantStore, staleMsgTimeOut, db, tracker, messageStore, logger))
+	r.Get(webhookURL, VerificationHandler(tenantStore, logger))
+	r.Mount(provisioningURL, provisioning.Router(envVars.ProvisioningSecret, tenantStore))
+	r.Get(bridgestateURL, bridgestate.Router(envVars.ProvisioningSecret, tracker).ServeHTTP)
+	r.Get(metricsURL, metrics.Handler().ServeHTTP)
 
 	// Define other routes
 	r.Get(returnBaseURL, PaymentReturnHandler(pymntRtrnTpl))
@@ -144,5 +141,5 @@ func main() {
 	//if err != nil {
 	//	log.Fatal(serverErr)
 	//}
-	log.Println("Server is running on port ", envVars.Port)
+	logger.Info("server is running", "port", envVars.Port)
 }
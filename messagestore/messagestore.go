@@ -0,0 +1,143 @@
+// Package messagestore persists inbound/outbound WhatsApp message IDs so
+// Meta's at-least-once delivery retries don't get processed twice, and so
+// delivery/read receipts can be queried after the fact.
+package messagestore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/JeremyJalpha/WhatsAppBot_CloudAPI/metrics"
+)
+
+// Store is a Postgres-backed record of every inbound message seen and
+// every outbound message's delivery lifecycle.
+type Store struct {
+	db *sql.DB
+}
+
+const createTablesSQL = `
+CREATE TABLE IF NOT EXISTS wa_inbound_messages (
+	id           TEXT PRIMARY KEY,
+	tenant_id    TEXT NOT NULL,
+	wa_id        TEXT NOT NULL,
+	received_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+	processed_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS wa_outbound_messages (
+	wamid       TEXT PRIMARY KEY,
+	tenant_id   TEXT NOT NULL,
+	sent_at     TIMESTAMPTZ,
+	delivered_at TIMESTAMPTZ,
+	read_at     TIMESTAMPTZ,
+	failed_at   TIMESTAMPTZ,
+	error_code  INT,
+	error_title TEXT
+)`
+
+// NewStore wraps db and ensures the message-tracking tables exist.
+func NewStore(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(createTablesSQL); err != nil {
+		return nil, fmt.Errorf("error creating message-tracking tables: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// InsertInbound records that tenantID received message id from waID. It
+// reports inserted=false when the row already existed, which callers must
+// treat as "already processed, do nothing" so Meta's webhook retries don't
+// re-run a conversation turn.
+func (s *Store) InsertInbound(tenantID, id, waID string) (inserted bool, err error) {
+	res, err := s.db.Exec(
+		`INSERT INTO wa_inbound_messages (id, tenant_id, wa_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		id, tenantID, waID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error recording inbound message %q: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking insert result for inbound message %q: %w", id, err)
+	}
+	return n > 0, nil
+}
+
+// MarkInboundProcessed timestamps when tenantID finished handling message
+// id.
+func (s *Store) MarkInboundProcessed(tenantID, id string) error {
+	_, err := s.db.Exec(
+		`UPDATE wa_inbound_messages SET processed_at = now() WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID,
+	)
+	if err != nil {
+		return fmt.Errorf("error marking inbound message %q processed: %w", id, err)
+	}
+	return nil
+}
+
+// OutboundStatus is the delivery lifecycle of one message we sent.
+type OutboundStatus struct {
+	Wamid       string     `json:"wamid"`
+	TenantID    string     `json:"tenant_id"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	FailedAt    *time.Time `json:"failed_at,omitempty"`
+	ErrorCode   *int       `json:"error_code,omitempty"`
+	ErrorTitle  *string    `json:"error_title,omitempty"`
+}
+
+// UpdateOutboundStatus applies a sent/delivered/read/failed transition for
+// wamid, creating the row on first sight since "sent" is usually the first
+// status Meta reports for a message we never explicitly recorded.
+func (s *Store) UpdateOutboundStatus(tenantID, wamid, status string, at time.Time, errorCode int, errorTitle string) error {
+	var column string
+	switch status {
+	case "sent":
+		column = "sent_at"
+	case "delivered":
+		column = "delivered_at"
+	case "read":
+		column = "read_at"
+	case "failed":
+		column = "failed_at"
+	default:
+		return fmt.Errorf("error, unknown outbound status %q for message %q", status, wamid)
+	}
+	metrics.OutboundStatusTotal.WithLabelValues(status).Inc()
+
+	var errCodeArg interface{}
+	var errTitleArg interface{}
+	if status == "failed" {
+		errCodeArg, errTitleArg = errorCode, errorTitle
+	}
+
+	_, err := s.db.Exec(
+		`INSERT INTO wa_outbound_messages (wamid, tenant_id, `+column+`, error_code, error_title)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (wamid) DO UPDATE SET `+column+` = $3, error_code = COALESCE($4, wa_outbound_messages.error_code),
+			error_title = COALESCE($5, wa_outbound_messages.error_title)`,
+		wamid, tenantID, at, errCodeArg, errTitleArg,
+	)
+	if err != nil {
+		return fmt.Errorf("error updating outbound message %q to status %q: %w", wamid, status, err)
+	}
+	return nil
+}
+
+// GetMessageStatus returns the delivery lifecycle recorded for wamid.
+func (s *Store) GetMessageStatus(wamid string) (*OutboundStatus, error) {
+	row := s.db.QueryRow(
+		`SELECT wamid, tenant_id, sent_at, delivered_at, read_at, failed_at, error_code, error_title
+		 FROM wa_outbound_messages WHERE wamid = $1`,
+		wamid,
+	)
+	var st OutboundStatus
+	if err := row.Scan(&st.Wamid, &st.TenantID, &st.SentAt, &st.DeliveredAt, &st.ReadAt, &st.FailedAt,
+		&st.ErrorCode, &st.ErrorTitle); err != nil {
+		return nil, fmt.Errorf("error getting status for outbound message %q: %w", wamid, err)
+	}
+	return &st, nil
+}
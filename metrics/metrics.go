@@ -0,0 +1,53 @@
+// Package metrics exposes the Prometheus counters and histograms for the
+// webhook path, scraped at GET /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	WebhookRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_requests_total",
+		Help: "Inbound POST /webhook requests, labeled by outcome.",
+	}, []string{"result"})
+
+	SignatureFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "signature_failures_total",
+		Help: "Inbound webhook requests rejected for a bad HMAC signature.",
+	})
+
+	StaleMessagesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "stale_messages_total",
+		Help: "Inbound text messages dropped for being older than the stale-message window.",
+	})
+
+	MessagesProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "messages_processed_total",
+		Help: "Inbound messages dispatched, labeled by message type.",
+	}, []string{"type"})
+
+	ChatBeginDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "chatbegin_duration_seconds",
+		Help: "Time spent in ChatClient.ChatBegin for one inbound text message.",
+	})
+
+	OutboundSendDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "outbound_send_duration_seconds",
+		Help: "Time spent sending an outbound reply via the Graph API, labeled by result.",
+	}, []string{"result"})
+
+	OutboundStatusTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbound_status_total",
+		Help: "Outbound message status transitions recorded from Meta's delivery receipts, labeled by status.",
+	}, []string{"status"})
+)
+
+// Handler serves the /metrics scrape endpoint.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
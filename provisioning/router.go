@@ -0,0 +1,183 @@
+package provisioning
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Router mounts the provisioning API at its caller-chosen base path. Every
+// request must present sharedSecret via the X-Provisioning-Secret header,
+// mirroring mautrix-whatsapp's ProvisioningAPI auth model.
+func Router(sharedSecret string, store *Store) http.Handler {
+	r := chi.NewRouter()
+	r.Use(authMiddleware(sharedSecret))
+
+	r.Get("/tenants", listTenants(store))
+	r.Post("/tenants", createTenant(store))
+	r.Get("/tenants/{id}", getTenant(store))
+	r.Put("/tenants/{id}", updateTenant(store))
+	r.Delete("/tenants/{id}", deleteTenant(store))
+	r.Post("/tenants/{id}/rotate_token", rotateToken(store))
+	r.Post("/tenants/{id}/rotate_app_secret", rotateAppSecret(store))
+
+	return r
+}
+
+func authMiddleware(sharedSecret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proffered := r.Header.Get("X-Provisioning-Secret")
+			if subtle.ConstantTimeCompare([]byte(proffered), []byte(sharedSecret)) != 1 {
+				http.Error(w, "error, invalid provisioning secret", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("error encoding provisioning response: " + err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	http.Error(w, err.Error(), status)
+	log.Println("provisioning error: " + err.Error())
+}
+
+func listTenants(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenants, err := store.List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		views := make([]TenantView, len(tenants))
+		for i, t := range tenants {
+			views[i] = t.Redacted()
+		}
+		writeJSON(w, http.StatusOK, views)
+	}
+}
+
+func createTenant(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var t Tenant
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if t.ID == "" || t.PhoneNumberID == "" {
+			writeError(w, http.StatusBadRequest, errors.New("error, id and phone_number_id are required"))
+			return
+		}
+		created, err := store.Create(t)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	}
+}
+
+func getTenant(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, err := store.Get(chi.URLParam(r, "id"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, tenant.Redacted())
+	}
+}
+
+// updateTenant applies a partial update: any field omitted (or sent as "")
+// in the request body keeps its existing value, so a PUT that only sets
+// host_number can't blank out the tenant's secrets.
+func updateTenant(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		existing, err := store.Get(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		var patch Tenant
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		merged := existing.mergeUpdate(patch)
+		updated, err := store.Update(id, merged)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, updated.Redacted())
+	}
+}
+
+func deleteTenant(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := store.Delete(chi.URLParam(r, "id")); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func rotateToken(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			WhatsAppToken string `json:"whatsapp_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if body.WhatsAppToken == "" {
+			writeError(w, http.StatusBadRequest, errors.New("error, whatsapp_token is required"))
+			return
+		}
+		updated, err := store.RotateToken(chi.URLParam(r, "id"), body.WhatsAppToken)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, updated.Redacted())
+	}
+}
+
+func rotateAppSecret(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			AppSecret string `json:"app_secret"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if body.AppSecret == "" {
+			writeError(w, http.StatusBadRequest, errors.New("error, app_secret is required"))
+			return
+		}
+		updated, err := store.RotateAppSecret(chi.URLParam(r, "id"), body.AppSecret)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, updated.Redacted())
+	}
+}
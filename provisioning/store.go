@@ -0,0 +1,201 @@
+package provisioning
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Store persists Tenant records in Postgres and resolves them by the
+// identifiers WhatsApp sends on the webhook payload.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore wraps db and ensures the tenants table exists.
+func NewStore(db *sql.DB) (*Store, error) {
+	s := &Store{db: db}
+	if _, err := db.Exec(createTenantsTableSQL); err != nil {
+		return nil, fmt.Errorf("error creating tenants table: %w", err)
+	}
+	return s, nil
+}
+
+const createTenantsTableSQL = `
+CREATE TABLE IF NOT EXISTS tenants (
+	id                  TEXT PRIMARY KEY,
+	phone_number_id     TEXT NOT NULL UNIQUE,
+	waba_id             TEXT NOT NULL,
+	whatsapp_token      TEXT NOT NULL,
+	verify_token        TEXT NOT NULL,
+	app_secret          TEXT NOT NULL,
+	previous_app_secret TEXT NOT NULL DEFAULT '',
+	host_number         TEXT NOT NULL,
+	homebase_url        TEXT NOT NULL DEFAULT '',
+	merchant_id         TEXT NOT NULL DEFAULT '',
+	merchant_key        TEXT NOT NULL DEFAULT '',
+	passphrase          TEXT NOT NULL DEFAULT '',
+	pf_host             TEXT NOT NULL DEFAULT '',
+	created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+const tenantColumns = "id, phone_number_id, waba_id, whatsapp_token, verify_token, app_secret, previous_app_secret, " +
+	"host_number, homebase_url, merchant_id, merchant_key, passphrase, pf_host, created_at, updated_at"
+
+func scanTenant(row interface{ Scan(...interface{}) error }) (*Tenant, error) {
+	var t Tenant
+	err := row.Scan(&t.ID, &t.PhoneNumberID, &t.WabaID, &t.WhatsAppToken, &t.VerifyToken, &t.AppSecret, &t.PreviousAppSecret,
+		&t.HostNumber, &t.HomebaseURL, &t.MerchantId, &t.MerchantKey, &t.Passphrase, &t.PfHost,
+		&t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create inserts a new tenant. The caller is responsible for assigning ID.
+func (s *Store) Create(t Tenant) (*Tenant, error) {
+	row := s.db.QueryRow(
+		`INSERT INTO tenants (id, phone_number_id, waba_id, whatsapp_token, verify_token, app_secret,
+			previous_app_secret, host_number, homebase_url, merchant_id, merchant_key, passphrase, pf_host)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING `+tenantColumns,
+		t.ID, t.PhoneNumberID, t.WabaID, t.WhatsAppToken, t.VerifyToken, t.AppSecret,
+		t.PreviousAppSecret, t.HostNumber, t.HomebaseURL, t.MerchantId, t.MerchantKey, t.Passphrase, t.PfHost,
+	)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error creating tenant %q: %w", t.ID, err)
+	}
+	return tenant, nil
+}
+
+// Get returns the tenant with the given ID.
+func (s *Store) Get(id string) (*Tenant, error) {
+	row := s.db.QueryRow(`SELECT `+tenantColumns+` FROM tenants WHERE id = $1`, id)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tenant %q: %w", id, err)
+	}
+	return tenant, nil
+}
+
+// GetByWabaID returns the tenant registered for the given WhatsApp Business
+// Account ID, as found in an inbound webhook's entry[].id.
+func (s *Store) GetByWabaID(wabaID string) (*Tenant, error) {
+	row := s.db.QueryRow(`SELECT `+tenantColumns+` FROM tenants WHERE waba_id = $1`, wabaID)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tenant for waba_id %q: %w", wabaID, err)
+	}
+	return tenant, nil
+}
+
+// GetByPhoneNumberID returns the tenant registered for the given Cloud API
+// phone_number_id, as found in an inbound webhook's metadata.phone_number_id.
+func (s *Store) GetByPhoneNumberID(phoneNumberID string) (*Tenant, error) {
+	row := s.db.QueryRow(`SELECT `+tenantColumns+` FROM tenants WHERE phone_number_id = $1`, phoneNumberID)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tenant for phone_number_id %q: %w", phoneNumberID, err)
+	}
+	return tenant, nil
+}
+
+// GetByVerifyToken returns the tenant whose verify token matches token, used
+// to service the GET /webhook subscription-verification handshake.
+func (s *Store) GetByVerifyToken(token string) (*Tenant, error) {
+	row := s.db.QueryRow(`SELECT `+tenantColumns+` FROM tenants WHERE verify_token = $1`, token)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tenant for verify token: %w", err)
+	}
+	return tenant, nil
+}
+
+// List returns every registered tenant, ordered by ID.
+func (s *Store) List() ([]Tenant, error) {
+	rows, err := s.db.Query(`SELECT ` + tenantColumns + ` FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning tenant row: %w", err)
+		}
+		tenants = append(tenants, *tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// Update overwrites the mutable fields of the tenant identified by id.
+func (s *Store) Update(id string, t Tenant) (*Tenant, error) {
+	row := s.db.QueryRow(
+		`UPDATE tenants SET phone_number_id = $2, waba_id = $3, whatsapp_token = $4, verify_token = $5,
+			app_secret = $6, previous_app_secret = $7, host_number = $8, homebase_url = $9, merchant_id = $10,
+			merchant_key = $11, passphrase = $12, pf_host = $13, updated_at = now()
+		 WHERE id = $1
+		 RETURNING `+tenantColumns,
+		id, t.PhoneNumberID, t.WabaID, t.WhatsAppToken, t.VerifyToken,
+		t.AppSecret, t.PreviousAppSecret, t.HostNumber, t.HomebaseURL, t.MerchantId, t.MerchantKey,
+		t.Passphrase, t.PfHost,
+	)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error updating tenant %q: %w", id, err)
+	}
+	return tenant, nil
+}
+
+// RotateToken swaps in a new WhatsApp access token for id without touching
+// any other tenant field, so an operator can recover from a stale token
+// without restarting the process.
+func (s *Store) RotateToken(id, newToken string) (*Tenant, error) {
+	row := s.db.QueryRow(
+		`UPDATE tenants SET whatsapp_token = $2, updated_at = now() WHERE id = $1 RETURNING `+tenantColumns,
+		id, newToken,
+	)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error rotating token for tenant %q: %w", id, err)
+	}
+	return tenant, nil
+}
+
+// RotateAppSecret swaps in a new webhook app secret for id, keeping the old
+// one as PreviousAppSecret so in-flight webhooks signed under it are still
+// accepted. The old secret stays accepted until it is replaced, by the next
+// rotation or by a PUT that sets a different non-empty previous_app_secret;
+// no endpoint clears it to empty.
+func (s *Store) RotateAppSecret(id, newSecret string) (*Tenant, error) {
+	row := s.db.QueryRow(
+		`UPDATE tenants SET previous_app_secret = app_secret, app_secret = $2, updated_at = now()
+		 WHERE id = $1 RETURNING `+tenantColumns,
+		id, newSecret,
+	)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		return nil, fmt.Errorf("error rotating app secret for tenant %q: %w", id, err)
+	}
+	return tenant, nil
+}
+
+// Delete removes the tenant identified by id.
+func (s *Store) Delete(id string) error {
+	res, err := s.db.Exec(`DELETE FROM tenants WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting tenant %q: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking delete result for tenant %q: %w", id, err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package provisioning implements a runtime operator API for registering
+// and managing WhatsApp Business tenants, so a single deployment can serve
+// more than one phone number without a restart.
+package provisioning
+
+import "time"
+
+// Tenant holds everything WebhookHandler and VerificationHandler need to
+// service a single WhatsApp Business phone number end to end: the Cloud API
+// credentials, the secrets used to authenticate inbound webhooks, and the
+// checkout credentials used when a conversation leads to a payment.
+type Tenant struct {
+	ID            string `json:"id"`
+	PhoneNumberID string `json:"phone_number_id"`
+	WabaID        string `json:"waba_id"`
+	WhatsAppToken string `json:"whatsapp_token"`
+	VerifyToken   string `json:"verify_token"`
+	AppSecret     string `json:"app_secret"`
+	// PreviousAppSecret, when set, is still accepted for webhook signature
+	// validation so an operator can rotate AppSecret without dropping
+	// in-flight webhooks.
+	PreviousAppSecret string    `json:"previous_app_secret"`
+	HostNumber        string    `json:"host_number"`
+	HomebaseURL       string    `json:"homebase_url"`
+	MerchantId        string    `json:"merchant_id"`
+	MerchantKey       string    `json:"merchant_key"`
+	Passphrase        string    `json:"passphrase"`
+	PfHost            string    `json:"pf_host"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TenantView is the representation of a Tenant returned by the read
+// endpoints (GET /tenants and GET /tenants/{id}) and by the update and
+// rotate endpoints. It mirrors Tenant but
+// omits every credential and secret, so holding the shared provisioning
+// secret is not enough to read out a tenant's WhatsApp token, app secrets,
+// or checkout credentials.
+type TenantView struct {
+	ID            string    `json:"id"`
+	PhoneNumberID string    `json:"phone_number_id"`
+	WabaID        string    `json:"waba_id"`
+	HostNumber    string    `json:"host_number"`
+	HomebaseURL   string    `json:"homebase_url"`
+	MerchantId    string    `json:"merchant_id"`
+	PfHost        string    `json:"pf_host"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Redacted returns the view of t safe to return from the read endpoints.
+func (t Tenant) Redacted() TenantView {
+	return TenantView{
+		ID:            t.ID,
+		PhoneNumberID: t.PhoneNumberID,
+		WabaID:        t.WabaID,
+		HostNumber:    t.HostNumber,
+		HomebaseURL:   t.HomebaseURL,
+		MerchantId:    t.MerchantId,
+		PfHost:        t.PfHost,
+		CreatedAt:     t.CreatedAt,
+		UpdatedAt:     t.UpdatedAt,
+	}
+}
+
+// mergeUpdate applies the non-zero string fields of patch onto a copy of t,
+// leaving any field patch omitted untouched. PUT /tenants/{id} is treated as
+// a partial update rather than a full replace, so an operator changing one
+// field (e.g. host_number) can't accidentally blank out the tenant's secrets
+// by omitting them from the request body.
+func (t Tenant) mergeUpdate(patch Tenant) Tenant {
+	merged := t
+	if patch.PhoneNumberID != "" {
+		merged.PhoneNumberID = patch.PhoneNumberID
+	}
+	if patch.WabaID != "" {
+		merged.WabaID = patch.WabaID
+	}
+	if patch.WhatsAppToken != "" {
+		merged.WhatsAppToken = patch.WhatsAppToken
+	}
+	if patch.VerifyToken != "" {
+		merged.VerifyToken = patch.VerifyToken
+	}
+	if patch.AppSecret != "" {
+		merged.AppSecret = patch.AppSecret
+	}
+	if patch.PreviousAppSecret != "" {
+		merged.PreviousAppSecret = patch.PreviousAppSecret
+	}
+	if patch.HostNumber != "" {
+		merged.HostNumber = patch.HostNumber
+	}
+	if patch.HomebaseURL != "" {
+		merged.HomebaseURL = patch.HomebaseURL
+	}
+	if patch.MerchantId != "" {
+		merged.MerchantId = patch.MerchantId
+	}
+	if patch.MerchantKey != "" {
+		merged.MerchantKey = patch.MerchantKey
+	}
+	if patch.Passphrase != "" {
+		merged.Passphrase = patch.Passphrase
+	}
+	if patch.PfHost != "" {
+		merged.PfHost = patch.PfHost
+	}
+	return merged
+}
@@ -0,0 +1,70 @@
+package webhook
+
+// Handler receives one callback per inbound message or status update, typed
+// by the message's content rather than requiring callers to branch on a
+// raw "type" string themselves.
+type Handler interface {
+	OnText(from string, msg Message, text TextMessage)
+	OnImage(from string, msg Message, image MediaMessage)
+	OnAudio(from string, msg Message, audio MediaMessage)
+	OnVideo(from string, msg Message, video MediaMessage)
+	OnDocument(from string, msg Message, document MediaMessage)
+	OnSticker(from string, msg Message, sticker MediaMessage)
+	OnLocation(from string, msg Message, location LocationMessage)
+	OnContacts(from string, msg Message, contacts []Contact)
+	OnInteractive(from string, msg Message, interactive InteractiveMsg)
+	OnButton(from string, msg Message, button ButtonMessage)
+	OnReaction(from string, msg Message, reaction ReactionMessage)
+	OnOrder(from string, msg Message, order OrderMessage)
+	OnSystem(from string, msg Message, system SystemMessage)
+	OnStatus(status Status)
+	OnUnknown(from string, msg Message)
+}
+
+// Dispatch walks the already-unmarshalled payload once and fans each
+// message and status out to the matching Handler method.
+func Dispatch(payload InboundWebhook, h Handler) {
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			for _, status := range change.Value.Statuses {
+				h.OnStatus(status)
+			}
+			for _, msg := range change.Value.Messages {
+				dispatchMessage(msg, h)
+			}
+		}
+	}
+}
+
+func dispatchMessage(msg Message, h Handler) {
+	switch msg.Type {
+	case MessageTypeText:
+		h.OnText(msg.From, msg, msg.Text)
+	case MessageTypeImage:
+		h.OnImage(msg.From, msg, msg.Image)
+	case MessageTypeAudio:
+		h.OnAudio(msg.From, msg, msg.Audio)
+	case MessageTypeVideo:
+		h.OnVideo(msg.From, msg, msg.Video)
+	case MessageTypeDocument:
+		h.OnDocument(msg.From, msg, msg.Document)
+	case MessageTypeSticker:
+		h.OnSticker(msg.From, msg, msg.Sticker)
+	case MessageTypeLocation:
+		h.OnLocation(msg.From, msg, msg.Location)
+	case MessageTypeContacts:
+		h.OnContacts(msg.From, msg, msg.Contacts)
+	case MessageTypeInteractive:
+		h.OnInteractive(msg.From, msg, msg.Interactive)
+	case MessageTypeButton:
+		h.OnButton(msg.From, msg, msg.Button)
+	case MessageTypeReaction:
+		h.OnReaction(msg.From, msg, msg.Reaction)
+	case MessageTypeOrder:
+		h.OnOrder(msg.From, msg, msg.Order)
+	case MessageTypeSystem:
+		h.OnSystem(msg.From, msg, msg.System)
+	default:
+		h.OnUnknown(msg.From, msg)
+	}
+}
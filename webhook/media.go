@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const graphAPIBase = "https://graph.facebook.com/v19.0"
+
+type mediaLookupResponse struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Sha256   string `json:"sha256"`
+	FileSize int64  `json:"file_size"`
+}
+
+// FetchMedia resolves mediaID to its download URL via the Graph API, using
+// token to authenticate, then streams the asset bytes to dst.
+func FetchMedia(token, mediaID string, dst io.Writer) error {
+	lookup, err := lookupMediaURL(token, mediaID)
+	if err != nil {
+		return fmt.Errorf("error resolving media %q: %w", mediaID, err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, lookup.URL, nil)
+	if err != nil {
+		return fmt.Errorf("error building media download request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading media %q: %w", mediaID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading media %q: unexpected status %d", mediaID, resp.StatusCode)
+	}
+
+	if _, err := io.Copy(dst, resp.Body); err != nil {
+		return fmt.Errorf("error streaming media %q: %w", mediaID, err)
+	}
+	return nil
+}
+
+func lookupMediaURL(token, mediaID string) (*mediaLookupResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, graphAPIBase+"/"+mediaID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error building media lookup request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting media lookup: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var lookup mediaLookupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&lookup); err != nil {
+		return nil, fmt.Errorf("error decoding media lookup response: %w", err)
+	}
+	return &lookup, nil
+}
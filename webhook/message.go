@@ -0,0 +1,184 @@
+// Package webhook models the WhatsApp Cloud API's inbound webhook schema in
+// full and dispatches each message to a caller-supplied Handler, so bot
+// authors don't have to re-parse the raw JSON for every message type.
+package webhook
+
+// InboundWebhook is the top-level payload Meta POSTs to the webhook URL for
+// both message and status notifications.
+type InboundWebhook struct {
+	Object string  `json:"object"`
+	Entry  []Entry `json:"entry"`
+}
+
+// Entry groups changes for a single WhatsApp Business Account.
+type Entry struct {
+	ID      string   `json:"id"`
+	Changes []Change `json:"changes"`
+}
+
+// Change carries one notification, identified by Field (typically
+// "messages").
+type Change struct {
+	Value Value  `json:"value"`
+	Field string `json:"field"`
+}
+
+// Value is the payload of a Change: the phone number it concerns, any
+// contacts, inbound messages, and delivery/read status updates.
+type Value struct {
+	MessagingProduct string    `json:"messaging_product"`
+	Metadata         Metadata  `json:"metadata"`
+	Contacts         []Contact `json:"contacts"`
+	Messages         []Message `json:"messages"`
+	Statuses         []Status  `json:"statuses"`
+}
+
+// Metadata identifies which of the account's phone numbers received the
+// notification.
+type Metadata struct {
+	DisplayPhoneNumber string `json:"display_phone_number"`
+	PhoneNumberID      string `json:"phone_number_id"`
+}
+
+// Contact is the sender's WhatsApp profile, as included alongside a message.
+type Contact struct {
+	Profile Profile `json:"profile"`
+	WaID    string  `json:"wa_id"`
+}
+
+// Profile holds the sender's display name.
+type Profile struct {
+	Name string `json:"name"`
+}
+
+// MessageType enumerates the Cloud API's inbound message types.
+type MessageType string
+
+const (
+	MessageTypeText        MessageType = "text"
+	MessageTypeImage       MessageType = "image"
+	MessageTypeAudio       MessageType = "audio"
+	MessageTypeVideo       MessageType = "video"
+	MessageTypeDocument    MessageType = "document"
+	MessageTypeSticker     MessageType = "sticker"
+	MessageTypeLocation    MessageType = "location"
+	MessageTypeContacts    MessageType = "contacts"
+	MessageTypeInteractive MessageType = "interactive"
+	MessageTypeButton      MessageType = "button"
+	MessageTypeReaction    MessageType = "reaction"
+	MessageTypeOrder       MessageType = "order"
+	MessageTypeSystem      MessageType = "system"
+)
+
+// Message is one inbound message. Only the field matching Type is populated.
+type Message struct {
+	From        string          `json:"from"`
+	ID          string          `json:"id"`
+	Timestamp   string          `json:"timestamp"`
+	Type        MessageType     `json:"type"`
+	Text        TextMessage     `json:"text"`
+	Image       MediaMessage    `json:"image"`
+	Audio       MediaMessage    `json:"audio"`
+	Video       MediaMessage    `json:"video"`
+	Document    MediaMessage    `json:"document"`
+	Sticker     MediaMessage    `json:"sticker"`
+	Location    LocationMessage `json:"location"`
+	Contacts    []Contact       `json:"contacts"`
+	Interactive InteractiveMsg  `json:"interactive"`
+	Button      ButtonMessage   `json:"button"`
+	Reaction    ReactionMessage `json:"reaction"`
+	Order       OrderMessage    `json:"order"`
+	System      SystemMessage   `json:"system"`
+}
+
+// TextMessage is the body of a plain-text message.
+type TextMessage struct {
+	Body string `json:"body"`
+}
+
+// MediaMessage references a downloadable asset by its media.id. Use
+// FetchMedia to resolve ID to bytes.
+type MediaMessage struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	Sha256   string `json:"sha256"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+}
+
+// LocationMessage is a shared pin.
+type LocationMessage struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name"`
+	Address   string  `json:"address"`
+}
+
+// InteractiveMsg is the reply to a list or reply-button prompt.
+type InteractiveMsg struct {
+	Type        string `json:"type"`
+	ButtonReply struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"button_reply"`
+	ListReply struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"list_reply"`
+}
+
+// ButtonMessage is a reply to a legacy quick-reply template button.
+type ButtonMessage struct {
+	Text    string `json:"text"`
+	Payload string `json:"payload"`
+}
+
+// ReactionMessage is an emoji reaction to a previous message.
+type ReactionMessage struct {
+	MessageID string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+// OrderMessage is a catalog order placed from a WhatsApp Business catalog.
+type OrderMessage struct {
+	CatalogID    string `json:"catalog_id"`
+	Text         string `json:"text"`
+	ProductItems []struct {
+		ProductRetailerID string `json:"product_retailer_id"`
+		Quantity          string `json:"quantity"`
+		ItemPrice         string `json:"item_price"`
+		Currency          string `json:"currency"`
+	} `json:"product_items"`
+}
+
+// SystemMessage is a system notification, e.g. a user changing numbers.
+type SystemMessage struct {
+	Body string `json:"body"`
+	Type string `json:"type"`
+}
+
+// Status is a delivery/read receipt for a message previously sent by us.
+type Status struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	Timestamp    string `json:"timestamp"`
+	RecipientID  string `json:"recipient_id"`
+	Conversation struct {
+		ID                  string `json:"id"`
+		ExpirationTimestamp string `json:"expiration_timestamp"`
+		Origin              struct {
+			Type string `json:"type"`
+		} `json:"origin"`
+	} `json:"conversation"`
+	Pricing struct {
+		Billable     bool   `json:"billable"`
+		PricingModel string `json:"pricing_model"`
+		Category     string `json:"category"`
+	} `json:"pricing"`
+	Errors []struct {
+		Code    int    `json:"code"`
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
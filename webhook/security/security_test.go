@@ -0,0 +1,96 @@
+package security
+
+import (
+	"net/http"
+	"testing"
+)
+
+func signedHeaders(body, secret []byte) http.Header {
+	h := http.Header{}
+	h.Set(signatureHeader, "sha256="+CalculateSignatureSha256(body, secret))
+	return h
+}
+
+func TestValidateSignature(t *testing.T) {
+	primary := []byte("primary-secret")
+	previous := []byte("previous-secret")
+
+	tests := []struct {
+		name    string
+		body    []byte
+		secrets [][]byte
+		headers func(body []byte) http.Header
+		wantErr bool
+	}{
+		{
+			name:    "ascii body matches primary secret",
+			body:    []byte(`{"object":"whatsapp_business_account"}`),
+			secrets: [][]byte{primary},
+			headers: func(body []byte) http.Header { return signedHeaders(body, primary) },
+		},
+		{
+			name:    "emoji body matches raw bytes, not the escaped form",
+			body:    []byte(`{"text":{"body":"hello 😀 world"}}`),
+			secrets: [][]byte{primary},
+			headers: func(body []byte) http.Header { return signedHeaders(body, primary) },
+		},
+		{
+			name:    "multi-byte CJK body matches raw bytes",
+			body:    []byte(`{"text":{"body":"你好，世界"}}`),
+			secrets: [][]byte{primary},
+			headers: func(body []byte) http.Header { return signedHeaders(body, primary) },
+		},
+		{
+			name:    "matches previous secret during rotation",
+			body:    []byte(`{"object":"whatsapp_business_account"}`),
+			secrets: [][]byte{primary, previous},
+			headers: func(body []byte) http.Header { return signedHeaders(body, previous) },
+		},
+		{
+			// Expected digest computed independently of CalculateSignatureSha256,
+			// via: printf '%s' '<body>' | openssl dgst -sha256 -hmac "it-is-a-secret" -hex
+			// This pins the implementation to a standard HMAC-SHA256 digest
+			// rather than only checking it agrees with itself.
+			name:    "matches an independently computed reference digest",
+			body:    []byte(`{"object":"whatsapp_business_account","entry":[{"id":"123456789"}]}`),
+			secrets: [][]byte{[]byte("it-is-a-secret")},
+			headers: func(body []byte) http.Header {
+				h := http.Header{}
+				h.Set(signatureHeader, "sha256=6bb1ec8cbf5e4573da67c3bed6b375cf12fc3afb4d44af19180fd475971b38f3")
+				return h
+			},
+		},
+		{
+			name:    "missing signature header",
+			body:    []byte(`{"object":"whatsapp_business_account"}`),
+			secrets: [][]byte{primary},
+			headers: func(body []byte) http.Header { return http.Header{} },
+			wantErr: true,
+		},
+		{
+			name:    "signature computed over escaped payload does not match raw-body validation",
+			body:    []byte(`{"text":{"body":"hello 😀 world"}}`),
+			secrets: [][]byte{primary},
+			headers: func(body []byte) http.Header {
+				return signedHeaders([]byte(EscapeNonASCIICharacters(string(body))), primary)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no secret matches",
+			body:    []byte(`{"object":"whatsapp_business_account"}`),
+			secrets: [][]byte{[]byte("wrong-secret")},
+			headers: func(body []byte) http.Header { return signedHeaders(body, primary) },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSignature(tt.headers(tt.body), tt.body, tt.secrets...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}